@@ -17,7 +17,11 @@
 package frugal
 
 import (
+    `fmt`
+
     `github.com/cloudwego/frugal/internal/binary/encoder`
+    compactdecoder `github.com/cloudwego/frugal/internal/compact/decoder`
+    compactencoder `github.com/cloudwego/frugal/internal/compact/encoder`
     `github.com/cloudwego/frugal/iov`
 )
 
@@ -30,3 +34,67 @@ func EncodedSize(val interface{}) int {
 func EncodeObject(buf []byte, mem iov.BufferWriter, val interface{}) (int, error) {
     return encoder.EncodeObject(buf, mem, val)
 }
+
+// SetCodeCacheBudget, SetAutoCodeCacheBudget and SetAutoCodeCacheFraction
+// are deliberately not exposed here yet: internal/binary/encoder has no
+// Cache-backed JIT encoder cache for them to configure in this source
+// tree (see the TODO(codegen-cache-wiring) note on internal/codecache),
+// so wrapping it here would just be a public API calling symbols that
+// don't exist. Add these once that package defines them.
+
+// Protocol identifies which Thrift wire protocol a Marshal call should
+// use.
+type Protocol uint8
+
+const (
+    ProtocolBinary  Protocol = iota // Thrift Binary Protocol
+    ProtocolCompact                 // Thrift Compact Protocol
+)
+
+func (self Protocol) String() string {
+    switch self {
+        case ProtocolBinary  : return "binary"
+        case ProtocolCompact : return "compact"
+        default               : return fmt.Sprintf("Protocol(%d)", uint8(self))
+    }
+}
+
+// EncodedSizeCompact measures the encoded size of val under the Thrift
+// Compact Protocol.
+//
+// Unlike EncodedSize/EncodeObject, this does not go through the JIT'd
+// internal/atm/ssa pipeline yet - internal/compact/encoder is a
+// reflection-based stopgap (see its doc comment) pending a tracked
+// follow-up to compile Compact Protocol encoders through the same
+// backend as Binary Protocol. Expect it to be slower than the Binary
+// Protocol path until then.
+func EncodedSizeCompact(val interface{}) int {
+    return compactencoder.EncodedSize(val)
+}
+
+// EncodeObjectCompact serializes val into buf with the Thrift Compact
+// Protocol. See EncodedSizeCompact for the current reflection-based
+// implementation's performance caveat relative to EncodeObject.
+func EncodeObjectCompact(buf []byte, mem iov.BufferWriter, val interface{}) (int, error) {
+    return compactencoder.EncodeObject(buf, mem, val)
+}
+
+// DecodeObjectCompact parses a Compact-Protocol encoded buf into val,
+// which must be a non-nil pointer. It returns the number of bytes
+// consumed from buf. As with EncodeObjectCompact, this runs through
+// internal/compact/decoder's reflection-based stopgap rather than a
+// JIT'd decoder.
+func DecodeObjectCompact(buf []byte, val interface{}) (int, error) {
+    return compactdecoder.DecodeObject(buf, val)
+}
+
+// Marshal encodes val with the given protocol, so callers negotiating
+// protocol at the RPC layer don't have to branch on EncodeObject vs.
+// EncodeObjectCompact themselves.
+func Marshal(proto Protocol, buf []byte, mem iov.BufferWriter, val interface{}) (int, error) {
+    switch proto {
+        case ProtocolBinary  : return EncodeObject(buf, mem, val)
+        case ProtocolCompact : return EncodeObjectCompact(buf, mem, val)
+        default               : return 0, fmt.Errorf("frugal: unknown protocol %s", proto)
+    }
+}
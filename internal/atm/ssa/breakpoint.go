@@ -0,0 +1,178 @@
+/*
+ * Copyright 2022 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ssa
+
+import (
+    `fmt`
+    `os`
+    `strconv`
+    `strings`
+    `sync`
+    `sync/atomic`
+    `unsafe`
+)
+
+// BreakpointHandler receives the registers an IrBreakpoint was told to
+// capture. `regs` holds general-purpose values keyed by the register's
+// String() form (e.g. "%r3"); `ptrs` holds the same for pointer
+// registers, kept separate so a handler never has to guess whether a
+// uint64 is actually a pointer.
+type BreakpointHandler func(regs map[string]uint64, ptrs map[string]unsafe.Pointer)
+
+var (
+    breakpointSeq  uint64
+    breakpointMu   sync.RWMutex
+    breakpointFns  = make(map[uint64]BreakpointHandler)
+)
+
+// NextBreakpointId allocates a fresh site ID for use with IrBreakpoint
+// and RegisterBreakpointHandler.
+func NextBreakpointId() uint64 {
+    return atomic.AddUint64(&breakpointSeq, 1)
+}
+
+// RegisterBreakpointHandler installs fn as the handler for breakpoint
+// site `id`. Registering nil removes the handler.
+func RegisterBreakpointHandler(id uint64, fn BreakpointHandler) {
+    breakpointMu.Lock()
+    defer breakpointMu.Unlock()
+
+    if fn == nil {
+        delete(breakpointFns, id)
+    } else {
+        breakpointFns[id] = fn
+    }
+}
+
+// dispatchBreakpoint looks up and invokes the handler for `id`, if any.
+// The codegen backend is meant to call this through the runtime call
+// table when it lowers an IrBreakpoint into a native call. See the
+// package doc comment on why no backend in this tree does that yet.
+func dispatchBreakpoint(id uint64, regs map[string]uint64, ptrs map[string]unsafe.Pointer) {
+    breakpointMu.RLock()
+    fn := breakpointFns[id]
+    breakpointMu.RUnlock()
+
+    if fn != nil {
+        fn(regs, ptrs)
+    }
+}
+
+// BreakpointMatcher decides whether a breakpoint should be inserted
+// around a given IrNode, and if so what tag to record on it.
+type BreakpointMatcher func(node IrNode) (tag string, ok bool)
+
+// InsertBreakpoints walks `ins` and, for every node that `match` selects,
+// inserts a new IrBreakpoint capturing that node's Usages() immediately
+// before it. It returns the resulting instruction slice; `ins` itself is
+// left untouched.
+//
+// This is meant for IrCall / IrStore / IrLoad chains, e.g.
+//
+//	ins = InsertBreakpoints(ins, func(node IrNode) (string, bool) {
+//	    call, ok := node.(*IrCall)
+//	    return "encode", ok && call.Fn.Name == "encodeField"
+//	})
+func InsertBreakpoints(ins []IrNode, match BreakpointMatcher) []IrNode {
+    ret := make([]IrNode, 0, len(ins))
+
+    for _, node := range ins {
+        if tag, ok := match(node); ok {
+            ret = append(ret, &IrBreakpoint{
+                Id:   NextBreakpointId(),
+                Regs: capturedRegs(node),
+                Tag:  tag,
+            })
+        }
+        ret = append(ret, node)
+    }
+
+    return ret
+}
+
+func capturedRegs(node IrNode) []Reg {
+    u, ok := node.(IrUsages)
+    if !ok {
+        return nil
+    }
+
+    regs := u.Usages()
+    ret := make([]Reg, 0, len(regs))
+
+    for _, r := range regs {
+        ret = append(ret, *r)
+    }
+
+    return ret
+}
+
+// FRUGAL_BREAK installs default breakpoint handlers without a rebuild,
+// e.g.
+//
+//	FRUGAL_BREAK=encode:field=17
+//
+// The part before ':' is just a label used in the dump; everything
+// after it is a comma-separated list of "tag=id" or bare numeric IDs.
+// Every matching site gets a handler that dumps its captured registers
+// to stderr.
+const breakpointEnv = "FRUGAL_BREAK"
+
+func init() {
+    if v := os.Getenv(breakpointEnv); v != "" {
+        installDefaultHandlers(v)
+    }
+}
+
+func installDefaultHandlers(spec string) {
+    label, rest := "", spec
+    if i := strings.IndexByte(spec, ':'); i >= 0 {
+        label, rest = spec[:i], spec[i+1:]
+    }
+
+    for _, part := range strings.Split(rest, ",") {
+        part = strings.TrimSpace(part)
+        if part == "" {
+            continue
+        }
+
+        tag := part
+        if i := strings.IndexByte(part, '='); i >= 0 {
+            tag = part[:i]
+            part = part[i+1:]
+        }
+
+        if id, err := strconv.ParseUint(part, 10, 64); err == nil {
+            RegisterBreakpointHandler(id, dumpHandler(label, tag))
+        }
+    }
+}
+
+// dumpHandler builds a BreakpointHandler that dumps its captured
+// registers to stderr, tagged with label (the part of FRUGAL_BREAK
+// before ':', e.g. "encode") and tag (the per-site "tag=id" name), so
+// the same FRUGAL_BREAK spec's dumps are distinguishable by caller
+// intent as well as by site.
+func dumpHandler(label string, tag string) BreakpointHandler {
+    name := tag
+    if label != "" {
+        name = label + ":" + tag
+    }
+
+    return func(regs map[string]uint64, ptrs map[string]unsafe.Pointer) {
+        fmt.Fprintf(os.Stderr, "[ssa:break:%s] regs=%v ptrs=%v\n", name, regs, ptrs)
+    }
+}
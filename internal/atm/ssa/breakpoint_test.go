@@ -0,0 +1,116 @@
+/*
+ * Copyright 2022 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ssa
+
+import (
+    `testing`
+    `unsafe`
+)
+
+func TestRegisterAndDispatchBreakpoint(t *testing.T) {
+    id := NextBreakpointId()
+    defer RegisterBreakpointHandler(id, nil)
+
+    var gotRegs map[string]uint64
+    var gotPtrs map[string]unsafe.Pointer
+
+    RegisterBreakpointHandler(id, func(regs map[string]uint64, ptrs map[string]unsafe.Pointer) {
+        gotRegs, gotPtrs = regs, ptrs
+    })
+
+    wantRegs := map[string]uint64{"%r1": 42}
+    wantPtrs := map[string]unsafe.Pointer{"%r2": nil}
+    dispatchBreakpoint(id, wantRegs, wantPtrs)
+
+    if len(gotRegs) != 1 || gotRegs["%r1"] != 42 {
+        t.Fatalf("handler saw regs %v, want %v", gotRegs, wantRegs)
+    }
+    if len(gotPtrs) != 1 {
+        t.Fatalf("handler saw ptrs %v, want %v", gotPtrs, wantPtrs)
+    }
+}
+
+func TestDispatchBreakpointWithoutHandler(t *testing.T) {
+    /* an id with no registered handler must be a silent no-op, not a panic */
+    dispatchBreakpoint(NextBreakpointId(), nil, nil)
+}
+
+func TestRegisterBreakpointHandlerNilRemoves(t *testing.T) {
+    id := NextBreakpointId()
+    called := false
+
+    RegisterBreakpointHandler(id, func(map[string]uint64, map[string]unsafe.Pointer) {
+        called = true
+    })
+    RegisterBreakpointHandler(id, nil)
+
+    dispatchBreakpoint(id, nil, nil)
+    if called {
+        t.Fatalf("handler should have been removed")
+    }
+}
+
+func TestInsertBreakpoints(t *testing.T) {
+    store := &IrStore{R: Reg(1), Mem: Reg(2), Size: 8}
+    ins := []IrNode{store}
+
+    out := InsertBreakpoints(ins, func(node IrNode) (string, bool) {
+        _, ok := node.(*IrStore)
+        return "store", ok
+    })
+
+    if len(out) != 2 {
+        t.Fatalf("len(out) = %d, want 2", len(out))
+    }
+
+    bp, ok := out[0].(*IrBreakpoint)
+    if !ok {
+        t.Fatalf("out[0] = %T, want *IrBreakpoint", out[0])
+    }
+    if bp.Tag != "store" {
+        t.Errorf("bp.Tag = %q, want %q", bp.Tag, "store")
+    }
+    if out[1] != store {
+        t.Errorf("out[1] = %v, want the original node untouched", out[1])
+    }
+
+    /* the input slice itself must be left alone */
+    if len(ins) != 1 || ins[0] != store {
+        t.Errorf("InsertBreakpoints mutated its input slice")
+    }
+}
+
+func TestInstallDefaultHandlersRegistersTaggedId(t *testing.T) {
+    breakpointMu.Lock()
+    breakpointFns = make(map[uint64]BreakpointHandler)
+    breakpointMu.Unlock()
+
+    installDefaultHandlers("encode:field=17")
+
+    handler := dumpHandler("encode", "field")
+    if handler == nil {
+        t.Fatalf("dumpHandler returned nil")
+    }
+
+    breakpointMu.RLock()
+    _, ok := breakpointFns[17]
+    breakpointMu.RUnlock()
+
+    if !ok {
+        t.Fatalf("installDefaultHandlers did not register a handler for id 17")
+    }
+}
@@ -14,6 +14,21 @@
  * limitations under the License.
  */
 
+// Package ssa models the IR the atm backend lowers hir.Ir into on its
+// way to native code: registers, IrNode and its concrete instructions,
+// basic-block successors, and the trace/breakpoint hooks a pass is
+// expected to report through.
+//
+// This source tree only carries that IR surface, not the backend built
+// on top of it - there is no BasicBlock builder, no pass pipeline
+// (regalloc, liveness, ...), and no codegen/emit package here to run
+// one. Consequently TracePass/TraceBlock/TraceNode (trace.go) and
+// dispatchBreakpoint (breakpoint.go) have no caller outside their own
+// tests: FRUGAL_TRACE and FRUGAL_BREAK are wired to accept input and do
+// the bookkeeping they document, but flipping them changes nothing
+// observable, because nothing in this tree lowers real IR through
+// them. Treat both as scaffolding for passes that land later, not as a
+// working trace/breakpoint feature today.
 package ssa
 
 import (
@@ -711,10 +726,32 @@ func (self *IrWriteBarrier) Usages() []*Reg {
     return []*Reg { &self.R, &self.V }
 }
 
-type (
-	IrBreakpoint struct{}
-)
+// IrBreakpoint is meant to lower to a call that hands control to a
+// Go-side handler registered with RegisterBreakpointHandler, identified
+// by Id (see dispatchBreakpoint for the current state of that backend
+// wiring). Regs names the registers to snapshot and pass to the
+// handler; Tag is an opaque label threaded through for the handler's
+// own bookkeeping (e.g. which Thrift field a matcher inserted this
+// breakpoint for).
+type IrBreakpoint struct {
+    Id   uint64
+    Regs []Reg
+    Tag  string
+}
+
+func (self *IrBreakpoint) String() string {
+    regs := make([]string, 0, len(self.Regs))
+    for _, r := range self.Regs {
+        regs = append(regs, r.String())
+    }
+
+    if self.Tag == "" {
+        return fmt.Sprintf("breakpoint #%d {%s}", self.Id, strings.Join(regs, ", "))
+    } else {
+        return fmt.Sprintf("breakpoint #%d<%s> {%s}", self.Id, self.Tag, strings.Join(regs, ", "))
+    }
+}
 
-func (IrBreakpoint) String() string {
-    return "breakpoint"
+func (self *IrBreakpoint) Usages() []*Reg {
+    return regsliceref(self.Regs)
 }
@@ -0,0 +1,169 @@
+/*
+ * Copyright 2022 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ssa
+
+import (
+    `fmt`
+    `os`
+    `strings`
+    `sync`
+    `sync/atomic`
+)
+
+// TraceLevel controls how much detail the SSA trace subsystem dumps for
+// a given pass. Levels are ordered, each one a superset of the previous.
+type TraceLevel int32
+
+const (
+    TraceOff    TraceLevel = iota // no dumps at all
+    TracePasses                   // one line per pass entry/exit
+    TraceBlocks                   // a dump of every BasicBlock the pass touches
+    TraceInsns                    // a dump of every IrNode within those blocks
+)
+
+func (self TraceLevel) String() string {
+    switch self {
+        case TraceOff    : return "off"
+        case TracePasses : return "passes"
+        case TraceBlocks : return "blocks"
+        case TraceInsns  : return "insns"
+        default          : return fmt.Sprintf("TraceLevel(%d)", int32(self))
+    }
+}
+
+func parseTraceLevel(s string) (TraceLevel, bool) {
+    switch strings.ToLower(strings.TrimSpace(s)) {
+        case "off"    : return TraceOff, true
+        case "passes" : return TracePasses, true
+        case "blocks" : return TraceBlocks, true
+        case "insns"  : return TraceInsns, true
+        default       : return TraceOff, false
+    }
+}
+
+var (
+    traceLevel  int32
+    traceMu     sync.RWMutex
+    tracePasses map[string]bool
+)
+
+// FRUGAL_TRACE enables the IR trace subsystem without a rebuild, e.g.
+//
+//     FRUGAL_TRACE=insns:regalloc,liveness
+//
+// dumps every instruction emitted or consumed by the "regalloc" and
+// "liveness" passes. The pass list is optional; omitting it traces every
+// pass at the given level.
+const traceEnv = "FRUGAL_TRACE"
+
+func init() {
+    if v := os.Getenv(traceEnv); v != "" {
+        loadTraceSpec(v)
+    }
+}
+
+func loadTraceSpec(spec string) {
+    level, passes := spec, ""
+    if i := strings.IndexByte(spec, ':'); i >= 0 {
+        level, passes = spec[:i], spec[i+1:]
+    }
+
+    /* only apply a recognized level; an unknown one leaves the current
+       level untouched, though the pass list below is still applied */
+    if lv, ok := parseTraceLevel(level); ok {
+        SetTraceLevel(lv)
+    }
+
+    /* an empty pass list clears the filter, tracing every pass */
+    if passes != "" {
+        SetPassFilter(strings.Split(passes, ",")...)
+    } else {
+        SetPassFilter()
+    }
+}
+
+// SetTraceLevel sets the global IR trace verbosity.
+func SetTraceLevel(level TraceLevel) {
+    atomic.StoreInt32(&traceLevel, int32(level))
+}
+
+// GetTraceLevel returns the current IR trace verbosity.
+func GetTraceLevel() TraceLevel {
+    return TraceLevel(atomic.LoadInt32(&traceLevel))
+}
+
+// SetPassFilter restricts dumps to the named passes, e.g.
+// SetPassFilter("regalloc", "liveness"). Calling it with no arguments
+// clears the filter so every pass is traced again.
+func SetPassFilter(passes ...string) {
+    traceMu.Lock()
+    defer traceMu.Unlock()
+
+    /* no filter means "trace everything" */
+    if len(passes) == 0 {
+        tracePasses = nil
+        return
+    }
+
+    /* otherwise only the named passes are traced */
+    tracePasses = make(map[string]bool, len(passes))
+    for _, p := range passes {
+        if p = strings.TrimSpace(p); p != "" {
+            tracePasses[p] = true
+        }
+    }
+}
+
+func passTraced(pass string) bool {
+    traceMu.RLock()
+    defer traceMu.RUnlock()
+    return tracePasses == nil || tracePasses[pass]
+}
+
+// enabled reports whether `pass` should be dumped at `level`.
+func enabled(pass string, level TraceLevel) bool {
+    return GetTraceLevel() >= level && passTraced(pass)
+}
+
+// TracePass, TraceBlock and TraceNode are the choke points every SSA
+// pass is meant to route its dumps through, so FRUGAL_TRACE can turn
+// them on without a rebuild. See the package doc comment on why that's
+// not yet true of anything in this tree.
+
+// TracePass logs a free-form message for `pass` if tracing is enabled at
+// or above TracePasses.
+func TracePass(pass string, format string, args ...interface{}) {
+    if enabled(pass, TracePasses) {
+        fmt.Fprintf(os.Stderr, "[ssa:%s] %s\n", pass, fmt.Sprintf(format, args...))
+    }
+}
+
+// TraceBlock dumps a single BasicBlock for `pass` if block-level tracing
+// is enabled.
+func TraceBlock(pass string, bb fmt.Stringer) {
+    if bb != nil && enabled(pass, TraceBlocks) {
+        fmt.Fprintf(os.Stderr, "[ssa:%s:blocks] %s\n", pass, bb)
+    }
+}
+
+// TraceNode dumps a single IrNode for `pass` if instruction-level
+// tracing is enabled.
+func TraceNode(pass string, node IrNode) {
+    if node != nil && enabled(pass, TraceInsns) {
+        fmt.Fprintf(os.Stderr, "[ssa:%s:insns] %s\n", pass, node)
+    }
+}
@@ -0,0 +1,113 @@
+/*
+ * Copyright 2022 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ssa
+
+import (
+    `testing`
+)
+
+func resetTraceState() {
+    SetTraceLevel(TraceOff)
+    SetPassFilter()
+}
+
+func TestParseTraceLevel(t *testing.T) {
+    cases := []struct {
+        in string
+        lv TraceLevel
+        ok bool
+    }{
+        {"off", TraceOff, true},
+        {"passes", TracePasses, true},
+        {"blocks", TraceBlocks, true},
+        {"insns", TraceInsns, true},
+        {"INSNS", TraceInsns, true},
+        {"  blocks  ", TraceBlocks, true},
+        {"bogus", TraceOff, false},
+        {"", TraceOff, false},
+    }
+
+    for _, c := range cases {
+        lv, ok := parseTraceLevel(c.in)
+        if lv != c.lv || ok != c.ok {
+            t.Errorf("parseTraceLevel(%q) = (%v, %v), want (%v, %v)", c.in, lv, ok, c.lv, c.ok)
+        }
+    }
+}
+
+func TestSetTraceLevel(t *testing.T) {
+    defer resetTraceState()
+
+    SetTraceLevel(TraceBlocks)
+    if GetTraceLevel() != TraceBlocks {
+        t.Fatalf("GetTraceLevel() = %v, want %v", GetTraceLevel(), TraceBlocks)
+    }
+}
+
+func TestPassFilter(t *testing.T) {
+    defer resetTraceState()
+
+    SetTraceLevel(TracePasses)
+
+    /* no filter: every pass is traced */
+    SetPassFilter()
+    if !enabled("regalloc", TracePasses) {
+        t.Fatalf("expected regalloc to be traced with no filter")
+    }
+
+    /* filtered: only named passes are traced */
+    SetPassFilter("regalloc", "liveness")
+    if !enabled("regalloc", TracePasses) {
+        t.Errorf("expected regalloc to be traced")
+    }
+    if enabled("deadcode", TracePasses) {
+        t.Errorf("expected deadcode not to be traced")
+    }
+
+    /* a level more detailed than the configured one is never traced, regardless of filter */
+    if enabled("regalloc", TraceInsns) {
+        t.Errorf("expected insns-level tracing to be disabled at TracePasses")
+    }
+}
+
+func TestLoadTraceSpec(t *testing.T) {
+    defer resetTraceState()
+
+    loadTraceSpec("insns:regalloc,liveness")
+
+    if GetTraceLevel() != TraceInsns {
+        t.Fatalf("GetTraceLevel() = %v, want %v", GetTraceLevel(), TraceInsns)
+    }
+    if !enabled("regalloc", TraceInsns) {
+        t.Errorf("expected regalloc to be traced")
+    }
+    if enabled("other", TraceInsns) {
+        t.Errorf("expected other to not be traced")
+    }
+
+    /* an unrecognized level leaves the current level untouched */
+    loadTraceSpec("bogus:regalloc")
+    if GetTraceLevel() != TraceInsns {
+        t.Errorf("unrecognized level changed GetTraceLevel() to %v", GetTraceLevel())
+    }
+
+    /* an empty pass list clears the filter */
+    loadTraceSpec("insns")
+    if !enabled("anything", TraceInsns) {
+        t.Errorf("expected an empty pass list to trace every pass")
+    }
+}
@@ -0,0 +1,179 @@
+/*
+ * Copyright 2024 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package codecache sizes the JIT encoder/decoder caches against the
+// memory the process is actually allowed to use, rather than the host's
+// total RAM. Container schedulers hand out a cgroup limit that is often
+// a tiny fraction of the node's memory, and a frugal-powered service
+// that keeps compiling and caching encoders against host RAM will run
+// right past that limit and get OOM-killed.
+//
+// TODO(codegen-cache-wiring): internal/binary/encoder (the JIT encoder
+// cache this package exists to bound) is not part of this source tree,
+// so Cache is not yet Get/Put-wired into an actual compiled-encoder
+// cache anywhere in this repo snapshot - SetBudget/SetAuto/SetFraction
+// only affect a Cache's own bookkeeping until that package's compiled-
+// encoder lookup is switched to go through a shared *Cache instance
+// here. Land that wiring before relying on this package to bound real
+// JIT memory use.
+package codecache
+
+import (
+    `container/list`
+    `sync`
+
+    `github.com/cloudwego/frugal/internal/memlimit`
+)
+
+// DefaultFraction is the share of the effective memory budget that a
+// Cache will grow into before it starts evicting, when auto-sizing is
+// enabled and no fraction has been set explicitly via SetFraction.
+const DefaultFraction = 0.05
+
+// Compiled is anything a Cache can hold: a JIT-compiled encoder or
+// decoder that knows its own code size and how to release it.
+type Compiled interface {
+    Size() int64
+    Release()
+}
+
+// Cache is an LRU cache of Compiled values bounded by total byte size
+// rather than entry count, since a compiled encoder's code size varies
+// wildly with the shape of the Thrift struct it was built for.
+type Cache struct {
+    mu       sync.Mutex
+    ls       *list.List
+    kv       map[interface{}]*list.Element
+    used     int64
+    budget   int64
+    auto     bool
+    fraction float64
+}
+
+type entry struct {
+    key   interface{}
+    value Compiled
+}
+
+// NewCache creates an empty Cache that auto-sizes its budget from the
+// process's effective memory limit until SetBudget is called.
+func NewCache() *Cache {
+    c := &Cache{
+        ls:       list.New(),
+        kv:       make(map[interface{}]*list.Element),
+        auto:     true,
+        fraction: DefaultFraction,
+    }
+    c.budget = c.autoBudget()
+    return c
+}
+
+func (self *Cache) autoBudget() int64 {
+    if limit, ok := memlimit.Effective(); ok {
+        return int64(float64(limit) * self.fraction)
+    }
+    return 0
+}
+
+// SetBudget pins the cache to an explicit byte budget and disables
+// auto-sizing. A non-positive value means "unbounded".
+func (self *Cache) SetBudget(bytes int64) {
+    self.mu.Lock()
+    defer self.mu.Unlock()
+    self.auto = false
+    self.budget = bytes
+    self.evictLocked()
+}
+
+// SetAuto re-enables (or disables) sizing the budget from the process's
+// effective memory limit. Disabling it leaves the last-known budget in
+// place until SetBudget is called explicitly.
+func (self *Cache) SetAuto(auto bool) {
+    self.mu.Lock()
+    defer self.mu.Unlock()
+    self.auto = auto
+    if auto {
+        self.budget = self.autoBudget()
+        self.evictLocked()
+    }
+}
+
+// SetFraction changes the share of the effective memory budget that
+// auto-sizing grows the cache into, overriding DefaultFraction. It
+// takes effect immediately if auto-sizing is currently enabled;
+// otherwise it is applied the next time SetAuto(true) runs.
+func (self *Cache) SetFraction(fraction float64) {
+    self.mu.Lock()
+    defer self.mu.Unlock()
+    self.fraction = fraction
+    if self.auto {
+        self.budget = self.autoBudget()
+        self.evictLocked()
+    }
+}
+
+// Get returns the cached value for key, promoting it to most-recently-used.
+func (self *Cache) Get(key interface{}) (Compiled, bool) {
+    self.mu.Lock()
+    defer self.mu.Unlock()
+
+    if el, ok := self.kv[key]; ok {
+        self.ls.MoveToFront(el)
+        return el.Value.(*entry).value, true
+    }
+
+    return nil, false
+}
+
+// Put inserts or replaces the cached value for key, evicting the
+// least-recently-used entries until the cache fits its budget.
+func (self *Cache) Put(key interface{}, value Compiled) {
+    self.mu.Lock()
+    defer self.mu.Unlock()
+
+    if el, ok := self.kv[key]; ok {
+        old := el.Value.(*entry)
+        self.used -= old.value.Size()
+        old.value.Release()
+        old.value = value
+        self.used += value.Size()
+        self.ls.MoveToFront(el)
+    } else {
+        self.kv[key] = self.ls.PushFront(&entry{key: key, value: value})
+        self.used += value.Size()
+    }
+
+    self.evictLocked()
+}
+
+func (self *Cache) evictLocked() {
+    if self.budget <= 0 {
+        return
+    }
+
+    for self.used > self.budget {
+        back := self.ls.Back()
+        if back == nil {
+            break
+        }
+
+        old := back.Value.(*entry)
+        self.ls.Remove(back)
+        delete(self.kv, old.key)
+        self.used -= old.value.Size()
+        old.value.Release()
+    }
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright 2024 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package codecache
+
+import (
+    `testing`
+
+    `github.com/cloudwego/frugal/internal/memlimit`
+)
+
+type fakeCompiled struct {
+    size     int64
+    released bool
+}
+
+func (self *fakeCompiled) Size() int64 { return self.size }
+func (self *fakeCompiled) Release()    { self.released = true }
+
+func TestCacheGetPut(t *testing.T) {
+    c := NewCache()
+    c.SetBudget(0) // unbounded, so Put/Get behavior is isolated from eviction
+
+    if _, ok := c.Get("a"); ok {
+        t.Fatalf("Get on empty cache should miss")
+    }
+
+    v := &fakeCompiled{size: 1}
+    c.Put("a", v)
+
+    got, ok := c.Get("a")
+    if !ok || got != v {
+        t.Fatalf("Get(%q) = (%v, %v), want (%v, true)", "a", got, ok, v)
+    }
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+    c := NewCache()
+    c.SetBudget(2)
+
+    a := &fakeCompiled{size: 1}
+    b := &fakeCompiled{size: 1}
+    d := &fakeCompiled{size: 1}
+
+    c.Put("a", a)
+    c.Put("b", b)
+
+    /* touch "a" so "b" becomes the least recently used entry */
+    c.Get("a")
+    c.Put("d", d)
+
+    if _, ok := c.Get("b"); ok {
+        t.Fatalf("expected \"b\" to have been evicted")
+    }
+    if !b.released {
+        t.Fatalf("expected evicted entry to be Released")
+    }
+
+    if _, ok := c.Get("a"); !ok {
+        t.Errorf("expected \"a\" to survive eviction")
+    }
+    if _, ok := c.Get("d"); !ok {
+        t.Errorf("expected \"d\" to survive eviction")
+    }
+}
+
+func TestCacheSetBudgetEvictsImmediately(t *testing.T) {
+    c := NewCache()
+    c.SetBudget(0)
+
+    c.Put("a", &fakeCompiled{size: 10})
+    c.Put("b", &fakeCompiled{size: 10})
+
+    c.SetBudget(10)
+
+    if _, ok := c.Get("a"); ok {
+        t.Fatalf("expected \"a\" to be evicted once the budget shrank below its size")
+    }
+    if _, ok := c.Get("b"); !ok {
+        t.Errorf("expected \"b\", the most recently used entry, to survive")
+    }
+}
+
+func TestCacheSetFraction(t *testing.T) {
+    c := NewCache()
+    c.SetAuto(true)
+
+    c.SetFraction(DefaultFraction * 2)
+    doubled := c.autoBudget()
+
+    /* autoBudget truncates limit*fraction, so the doubled budget is
+       compared against that same product rather than twice the old one */
+    if limit, ok := memlimit.Effective(); ok {
+        if want := int64(float64(limit) * (DefaultFraction * 2)); doubled != want {
+            t.Errorf("autoBudget() after doubling the fraction = %d, want %d", doubled, want)
+        }
+    }
+}
@@ -0,0 +1,277 @@
+/*
+ * Copyright 2024 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package compact holds the wire-format primitives shared by
+// internal/compact/encoder and internal/compact/decoder: the type
+// codes, varint/zigzag codecs, and field/list/map header packing rules
+// defined by the Thrift Compact Protocol.
+//
+// TODO(compact-jit): internal/compact/encoder and internal/compact/decoder
+// currently drive these primitives from reflection rather than the
+// internal/atm/ssa pipeline that the Binary Protocol codecs compile
+// through. That was a deliberate stopgap to ship a working Compact
+// Protocol path without first teaching the SSA backend compact's
+// field-header/zig-zag rules, not the intended long-term shape of this
+// package - callers should not expect JIT'd speed from it yet. This
+// package itself only describes the bytes on the wire, independent of
+// how a caller gets there, so it is unaffected either way.
+package compact
+
+import (
+    `fmt`
+    `reflect`
+    `strconv`
+    `strings`
+)
+
+// Type is a Compact Protocol field/element type code, as it appears
+// packed into a field header or a list/set/map header.
+type Type uint8
+
+const (
+    STOP          Type = 0x00
+    BOOL_TRUE     Type = 0x01
+    BOOL_FALSE    Type = 0x02
+    BYTE          Type = 0x03
+    I16           Type = 0x04
+    I32           Type = 0x05
+    I64           Type = 0x06
+    DOUBLE        Type = 0x07
+    BINARY        Type = 0x08
+    LIST          Type = 0x09
+    SET           Type = 0x0a
+    MAP           Type = 0x0b
+    STRUCT        Type = 0x0c
+)
+
+// MaxDeltaId is the largest field-ID delta that fits in the 4-bit
+// "short form" of a field header; anything larger falls back to the
+// long form (a zero delta nibble followed by a zig-zagged i16 ID).
+const MaxDeltaId = 15
+
+// PackFieldHeader encodes a struct field header. When the field's ID is
+// within MaxDeltaId of the previous field written, it packs into a
+// single byte: the 4-bit delta in the high nibble and the type code in
+// the low nibble. Otherwise it returns ok=false and the caller must
+// emit a zero delta nibble followed by the zig-zagged absolute ID.
+func PackFieldHeader(lastId int16, id int16, typ Type) (b byte, ok bool) {
+    delta := int32(id) - int32(lastId)
+
+    if delta <= 0 || delta > MaxDeltaId {
+        return 0, false
+    }
+
+    return byte(delta)<<4 | byte(typ)&0x0f, true
+}
+
+// UnpackFieldHeader splits a short-form field header byte back into its
+// delta and type code. The long form (delta == 0) carries no ID here;
+// the caller must read the zig-zagged i16 that follows separately.
+func UnpackFieldHeader(b byte) (delta int16, typ Type) {
+    return int16(b >> 4), Type(b & 0x0f)
+}
+
+// ZigZag32 maps a signed i32 onto the unsigned range so that small
+// magnitudes (positive or negative) encode as small varints.
+func ZigZag32(v int32) uint32 {
+    return uint32(v<<1) ^ uint32(v>>31)
+}
+
+// UnZigZag32 is the inverse of ZigZag32.
+func UnZigZag32(v uint32) int32 {
+    return int32(v>>1) ^ -int32(v&1)
+}
+
+// ZigZag64 maps a signed i64 onto the unsigned range so that small
+// magnitudes (positive or negative) encode as small varints.
+func ZigZag64(v int64) uint64 {
+    return uint64(v<<1) ^ uint64(v>>63)
+}
+
+// UnZigZag64 is the inverse of ZigZag64.
+func UnZigZag64(v uint64) int64 {
+    return int64(v>>1) ^ -int64(v&1)
+}
+
+// PutVarint32 appends the base-128 varint encoding of v to buf and
+// returns the extended slice.
+func PutVarint32(buf []byte, v uint32) []byte {
+    for v >= 0x80 {
+        buf = append(buf, byte(v)|0x80)
+        v >>= 7
+    }
+    return append(buf, byte(v))
+}
+
+// PutVarint64 appends the base-128 varint encoding of v to buf and
+// returns the extended slice.
+func PutVarint64(buf []byte, v uint64) []byte {
+    for v >= 0x80 {
+        buf = append(buf, byte(v)|0x80)
+        v >>= 7
+    }
+    return append(buf, byte(v))
+}
+
+// GetVarint32 reads a base-128 varint from the front of buf, returning
+// the decoded value and the number of bytes consumed. It returns n == 0
+// if buf does not contain a complete varint.
+func GetVarint32(buf []byte) (v uint32, n int) {
+    var shift uint
+
+    for n < len(buf) && n < 5 {
+        b := buf[n]
+        v |= uint32(b&0x7f) << shift
+        n++
+
+        if b < 0x80 {
+            return v, n
+        }
+
+        shift += 7
+    }
+
+    return 0, 0
+}
+
+// GetVarint64 reads a base-128 varint from the front of buf, returning
+// the decoded value and the number of bytes consumed. It returns n == 0
+// if buf does not contain a complete varint.
+func GetVarint64(buf []byte) (v uint64, n int) {
+    var shift uint
+
+    for n < len(buf) && n < 10 {
+        b := buf[n]
+        v |= uint64(b&0x7f) << shift
+        n++
+
+        if b < 0x80 {
+            return v, n
+        }
+
+        shift += 7
+    }
+
+    return 0, 0
+}
+
+// PackListHeader packs a list/set element count and type the same way
+// the Compact Protocol does for field headers: counts under 15 pack
+// into the header byte's high nibble, otherwise the nibble is set to
+// 0xf and the real count follows as a separate varint.
+func PackListHeader(size int, typ Type) (b byte, long bool) {
+    if size < 0 || size >= 15 {
+        return 0xf0 | byte(typ)&0x0f, true
+    }
+    return byte(size)<<4 | byte(typ)&0x0f, false
+}
+
+// UnpackListHeader is the inverse of PackListHeader; long reports
+// whether the caller still needs to read the real count as a varint.
+func UnpackListHeader(b byte) (size int, typ Type, long bool) {
+    n := int(b >> 4)
+    return n, Type(b & 0x0f), n == 0x0f
+}
+
+// PackMapHeader appends a map header: a varint element count, followed
+// by a single key-type/value-type byte (key type in the high nibble,
+// value type in the low nibble). Per the Compact Protocol spec, an
+// empty map omits the type byte entirely and is just a single zero
+// byte.
+func PackMapHeader(buf []byte, size int, keyType Type, valType Type) []byte {
+    buf = PutVarint32(buf, uint32(size))
+
+    if size > 0 {
+        buf = append(buf, byte(keyType)<<4|byte(valType)&0x0f)
+    }
+
+    return buf
+}
+
+// UnpackMapHeader is the inverse of PackMapHeader; n is the number of
+// bytes consumed from the front of buf. keyType and valType are
+// meaningless when size == 0, matching the "no type byte" encoding of
+// an empty map.
+func UnpackMapHeader(buf []byte) (size int, keyType Type, valType Type, n int, err error) {
+    v, m := GetVarint32(buf)
+
+    if m == 0 {
+        return 0, 0, 0, 0, errTruncated("map size")
+    }
+
+    size, n = int(v), m
+    if size == 0 {
+        return 0, 0, 0, n, nil
+    }
+
+    if n >= len(buf) {
+        return 0, 0, 0, 0, errTruncated("map key/value type")
+    }
+
+    keyType = Type(buf[n] >> 4)
+    valType = Type(buf[n] & 0x0f)
+    return size, keyType, valType, n + 1, nil
+}
+
+func errTruncated(what string) error {
+    return fmt.Errorf("compact: truncated %s", what)
+}
+
+// FieldTag is the struct tag key used to carry a field's Thrift field
+// ID, e.g. `frugal:"1"`. Unlike the Binary Protocol path, which derives
+// field IDs from generated code driven through internal/atm/hir, the
+// reflection-based compact codec has no such metadata to fall back on,
+// so a struct without this tag cannot be safely encoded: inventing an
+// ID from declaration order would silently desync from any peer using
+// the real field ID, especially after schema evolution reorders or
+// removes fields.
+const FieldTag = "frugal"
+
+// TagFieldId reads the Thrift field ID out of f's FieldTag struct tag.
+// It returns ok=false if the tag is absent or malformed, in which case
+// the caller must refuse to encode/decode the field rather than guess.
+func TagFieldId(f reflect.StructField) (int16, bool) {
+    tag, has := f.Tag.Lookup(FieldTag)
+    if !has {
+        return 0, false
+    }
+
+    if i := strings.IndexByte(tag, ','); i >= 0 {
+        tag = tag[:i]
+    }
+
+    id, err := strconv.ParseInt(strings.TrimSpace(tag), 10, 16)
+    if err != nil {
+        return 0, false
+    }
+
+    return int16(id), true
+}
+
+// FieldByTag finds the struct field of t tagged with the given Thrift
+// field ID, mirroring TagFieldId for the decode direction. It returns
+// ok=false if no field carries that ID, in which case the caller should
+// treat the field as unknown (e.g. skip it) rather than guess by
+// position.
+func FieldByTag(t reflect.Type, id int16) (int, bool) {
+    for i := 0; i < t.NumField(); i++ {
+        if fid, ok := TagFieldId(t.Field(i)); ok && fid == id {
+            return i, true
+        }
+    }
+
+    return 0, false
+}
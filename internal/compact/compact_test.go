@@ -0,0 +1,157 @@
+/*
+ * Copyright 2024 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package compact
+
+import (
+    `reflect`
+    `testing`
+)
+
+func TestFieldHeaderRoundTrip(t *testing.T) {
+    b, ok := PackFieldHeader(3, 5, I32)
+    if !ok {
+        t.Fatalf("PackFieldHeader should use the short form for a delta of 2")
+    }
+
+    delta, typ := UnpackFieldHeader(b)
+    if delta != 2 || typ != I32 {
+        t.Fatalf("UnpackFieldHeader(%#x) = (%d, %v), want (2, %v)", b, delta, typ, I32)
+    }
+
+    if _, ok := PackFieldHeader(3, 3, I32); ok {
+        t.Errorf("a non-positive delta must fall back to the long form")
+    }
+    if _, ok := PackFieldHeader(0, 17, I32); ok {
+        t.Errorf("a delta over MaxDeltaId must fall back to the long form")
+    }
+}
+
+func TestZigZagRoundTrip(t *testing.T) {
+    for _, v := range []int32{0, 1, -1, 2147483647, -2147483648} {
+        if got := UnZigZag32(ZigZag32(v)); got != v {
+            t.Errorf("UnZigZag32(ZigZag32(%d)) = %d", v, got)
+        }
+    }
+    for _, v := range []int64{0, 1, -1, 9223372036854775807, -9223372036854775808} {
+        if got := UnZigZag64(ZigZag64(v)); got != v {
+            t.Errorf("UnZigZag64(ZigZag64(%d)) = %d", v, got)
+        }
+    }
+}
+
+func TestVarintRoundTrip(t *testing.T) {
+    for _, v := range []uint32{0, 1, 127, 128, 16384, 4294967295} {
+        buf := PutVarint32(nil, v)
+        got, n := GetVarint32(buf)
+        if n != len(buf) || got != v {
+            t.Errorf("varint32 round trip for %d: got (%d, %d), want (%d, %d)", v, got, n, v, len(buf))
+        }
+    }
+
+    if _, n := GetVarint32([]byte{0x80, 0x80}); n != 0 {
+        t.Errorf("GetVarint32 of a truncated varint should report n == 0, got %d", n)
+    }
+}
+
+func TestListHeaderRoundTrip(t *testing.T) {
+    b, long := PackListHeader(3, BYTE)
+    if long {
+        t.Fatalf("a count under 15 must use the short form")
+    }
+    size, typ, long := UnpackListHeader(b)
+    if size != 3 || typ != BYTE || long {
+        t.Fatalf("UnpackListHeader(%#x) = (%d, %v, %v), want (3, %v, false)", b, size, typ, long, BYTE)
+    }
+
+    b, long = PackListHeader(15, I64)
+    if !long {
+        t.Fatalf("a count of 15 must overflow into the long form")
+    }
+
+    /* the long form still has to carry the element type in its low
+       nibble and the 0xf marker in its high nibble - UnpackListHeader
+       must see it as long, not silently decode a size-0 short form */
+    size, typ, long = UnpackListHeader(b)
+    if !long || typ != I64 {
+        t.Fatalf("UnpackListHeader(%#x) = (%d, %v, %v), want (_, %v, true)", b, size, typ, long, I64)
+    }
+}
+
+func TestMapHeaderRoundTrip(t *testing.T) {
+    buf := PackMapHeader(nil, 3, I32, BINARY)
+    size, keyType, valType, n, err := UnpackMapHeader(buf)
+    if err != nil {
+        t.Fatalf("UnpackMapHeader: %v", err)
+    }
+    if size != 3 || keyType != I32 || valType != BINARY || n != len(buf) {
+        t.Fatalf("UnpackMapHeader = (%d, %v, %v, %d), want (3, %v, %v, %d)", size, keyType, valType, n, I32, BINARY, len(buf))
+    }
+}
+
+func TestMapHeaderEmptyOmitsTypeByte(t *testing.T) {
+    buf := PackMapHeader(nil, 0, I32, BINARY)
+    if len(buf) != 1 {
+        t.Fatalf("an empty map header should be a single byte, got %d", len(buf))
+    }
+
+    size, _, _, n, err := UnpackMapHeader(buf)
+    if err != nil {
+        t.Fatalf("UnpackMapHeader: %v", err)
+    }
+    if size != 0 || n != 1 {
+        t.Fatalf("UnpackMapHeader of an empty map = (%d, n=%d), want (0, n=1)", size, n)
+    }
+}
+
+func TestUnpackMapHeaderTruncated(t *testing.T) {
+    if _, _, _, _, err := UnpackMapHeader(nil); err == nil {
+        t.Fatalf("expected an error for an empty buffer")
+    }
+
+    /* a non-zero size with no trailing type byte must fail, not read past buf */
+    buf := PutVarint32(nil, 1)
+    if _, _, _, _, err := UnpackMapHeader(buf); err == nil {
+        t.Fatalf("expected an error for a missing key/value type byte")
+    }
+}
+
+func TestTagFieldIdAndFieldByTag(t *testing.T) {
+    type S struct {
+        A int16  `frugal:"1"`
+        B string `frugal:"3,omitempty"`
+        C int64
+    }
+
+    typ := reflect.TypeOf(S{})
+
+    if id, ok := TagFieldId(typ.Field(0)); !ok || id != 1 {
+        t.Errorf("TagFieldId(A) = (%d, %v), want (1, true)", id, ok)
+    }
+    if id, ok := TagFieldId(typ.Field(1)); !ok || id != 3 {
+        t.Errorf("TagFieldId(B) = (%d, %v), want (3, true)", id, ok)
+    }
+    if _, ok := TagFieldId(typ.Field(2)); ok {
+        t.Errorf("TagFieldId(C) should fail: C has no frugal tag")
+    }
+
+    if idx, ok := FieldByTag(typ, 3); !ok || idx != 1 {
+        t.Errorf("FieldByTag(3) = (%d, %v), want (1, true)", idx, ok)
+    }
+    if _, ok := FieldByTag(typ, 99); ok {
+        t.Errorf("FieldByTag(99) should fail: no field carries that ID")
+    }
+}
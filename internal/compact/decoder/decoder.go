@@ -0,0 +1,489 @@
+/*
+ * Copyright 2024 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package decoder implements the Thrift Compact Protocol decoder, the
+// counterpart to internal/compact/encoder. See that package's doc
+// comment, and the TODO(compact-jit) note on package compact, for why
+// this is a reflection-based stopgap rather than an SSA-compiled
+// decoder.
+//
+// Struct fields are matched against the wire's field IDs via
+// compact.FieldTag, the same tag internal/compact/encoder requires for
+// encoding; a field with no matching ID is treated as unknown and
+// skipped rather than guessed by position.
+package decoder
+
+import (
+    `encoding/binary`
+    `fmt`
+    `math`
+    `reflect`
+
+    `github.com/cloudwego/frugal/internal/compact`
+)
+
+// DecodeObject parses a Compact-Protocol encoded struct from buf into
+// val, which must be a non-nil pointer. It returns the number of bytes
+// consumed from buf.
+func DecodeObject(buf []byte, val interface{}) (int, error) {
+    v := reflect.ValueOf(val)
+
+    if v.Kind() != reflect.Ptr || v.IsNil() {
+        return 0, fmt.Errorf("compact: decode target must be a non-nil pointer, got %T", val)
+    }
+
+    n, err := decode(buf, v.Elem())
+    if err != nil {
+        return 0, err
+    }
+
+    return n, nil
+}
+
+func decode(buf []byte, v reflect.Value) (int, error) {
+    if v.Kind() == reflect.Ptr {
+        if v.IsNil() {
+            v.Set(reflect.New(v.Type().Elem()))
+        }
+        return decode(buf, v.Elem())
+    }
+
+    switch v.Kind() {
+        case reflect.Bool:
+            /* a struct field's bool arrives packed into its header
+               nibble and is decoded in decodeStruct instead; everywhere
+               else - list/set elements, map keys/values, a bare
+               top-level bool - it's its own BOOL_TRUE/BOOL_FALSE byte,
+               the mirror of encoder.go's encode() */
+            if len(buf) < 1 {
+                return 0, fmt.Errorf("compact: truncated bool")
+            }
+            switch compact.Type(buf[0]) {
+                case compact.BOOL_TRUE:
+                    v.SetBool(true)
+                case compact.BOOL_FALSE:
+                    v.SetBool(false)
+                default:
+                    return 0, fmt.Errorf("compact: %v is not a valid bool wire type", compact.Type(buf[0]))
+            }
+            return 1, nil
+
+        case reflect.Int8, reflect.Uint8:
+            if len(buf) < 1 {
+                return 0, fmt.Errorf("compact: truncated byte")
+            }
+            if v.Kind() == reflect.Uint8 {
+                v.SetUint(uint64(buf[0]))
+            } else {
+                v.SetInt(int64(int8(buf[0])))
+            }
+            return 1, nil
+
+        case reflect.Int16, reflect.Int32:
+            x, n := compact.GetVarint32(buf)
+            if n == 0 {
+                return 0, fmt.Errorf("compact: truncated varint")
+            }
+            v.SetInt(int64(compact.UnZigZag32(x)))
+            return n, nil
+
+        case reflect.Int64, reflect.Int:
+            x, n := compact.GetVarint64(buf)
+            if n == 0 {
+                return 0, fmt.Errorf("compact: truncated varint")
+            }
+            v.SetInt(compact.UnZigZag64(x))
+            return n, nil
+
+        case reflect.Float32, reflect.Float64:
+            if len(buf) < 8 {
+                return 0, fmt.Errorf("compact: truncated double")
+            }
+            v.SetFloat(math.Float64frombits(binary.BigEndian.Uint64(buf)))
+            return 8, nil
+
+        case reflect.String:
+            p, n, err := decodeBinary(buf)
+            if err != nil {
+                return 0, err
+            }
+            v.SetString(string(p))
+            return n, nil
+
+        case reflect.Slice, reflect.Array:
+            if v.Type().Elem().Kind() == reflect.Uint8 {
+                p, n, err := decodeBinary(buf)
+                if err != nil {
+                    return 0, err
+                }
+                v.SetBytes(p)
+                return n, nil
+            }
+            return decodeList(buf, v)
+
+        case reflect.Map:
+            return decodeMap(buf, v)
+
+        case reflect.Struct:
+            return decodeStruct(buf, v)
+
+        default:
+            return 0, fmt.Errorf("compact: unsupported kind %s", v.Kind())
+    }
+}
+
+func decodeBinary(buf []byte) ([]byte, int, error) {
+    size, n := compact.GetVarint32(buf)
+    if n == 0 {
+        return nil, 0, fmt.Errorf("compact: truncated binary length")
+    }
+
+    end := n + int(size)
+    if end > len(buf) {
+        return nil, 0, fmt.Errorf("compact: truncated binary payload")
+    }
+
+    return buf[n:end], end, nil
+}
+
+// wireTypeMatches reports whether a value of wire type typ can be
+// decoded into a Go field of type t without the target Kind switch in
+// decode/decodeStruct going down the wrong case (e.g. SetBool on a
+// non-bool field). It mirrors encoder.wireType in reverse.
+func wireTypeMatches(typ compact.Type, t reflect.Type) bool {
+    for t.Kind() == reflect.Ptr {
+        t = t.Elem()
+    }
+
+    switch t.Kind() {
+        case reflect.Bool:
+            return typ == compact.BOOL_TRUE || typ == compact.BOOL_FALSE
+        case reflect.Int8, reflect.Uint8:
+            return typ == compact.BYTE
+        case reflect.Int16:
+            return typ == compact.I16
+        case reflect.Int32:
+            return typ == compact.I32
+        case reflect.Int64, reflect.Int:
+            return typ == compact.I64
+        case reflect.Float32, reflect.Float64:
+            return typ == compact.DOUBLE
+        case reflect.String:
+            return typ == compact.BINARY
+        case reflect.Map:
+            return typ == compact.MAP
+        case reflect.Struct:
+            return typ == compact.STRUCT
+        case reflect.Slice, reflect.Array:
+            if t.Elem().Kind() == reflect.Uint8 {
+                return typ == compact.BINARY
+            }
+            return typ == compact.LIST || typ == compact.SET
+        default:
+            return false
+    }
+}
+
+func decodeList(buf []byte, v reflect.Value) (int, error) {
+    if len(buf) < 1 {
+        return 0, fmt.Errorf("compact: truncated list header")
+    }
+
+    size, _, long := compact.UnpackListHeader(buf[0])
+    off := 1
+
+    if long {
+        n, m := compact.GetVarint32(buf[off:])
+        if m == 0 {
+            return 0, fmt.Errorf("compact: truncated list size")
+        }
+        size, off = int(n), off+m
+    }
+
+    /* every element takes at least one byte on the wire, so a claimed
+       size larger than what's left of buf is a malformed/hostile
+       header - reject it before MakeSlice turns it into a multi-GB
+       allocation */
+    if size < 0 || size > len(buf)-off {
+        return 0, fmt.Errorf("compact: list size %d exceeds remaining buffer", size)
+    }
+
+    out := reflect.MakeSlice(v.Type(), size, size)
+
+    for i := 0; i < size; i++ {
+        n, err := decode(buf[off:], out.Index(i))
+        if err != nil {
+            return 0, err
+        }
+        off += n
+    }
+
+    v.Set(out)
+    return off, nil
+}
+
+func decodeMap(buf []byte, v reflect.Value) (int, error) {
+    size, _, _, off, err := compact.UnpackMapHeader(buf)
+    if err != nil {
+        return 0, err
+    }
+
+    /* as in decodeList, each entry needs at least two bytes (a key and
+       a value), so bound the claimed count against what's actually
+       left of buf before committing to a map of that size */
+    if size < 0 || size > (len(buf)-off)/2 {
+        return 0, fmt.Errorf("compact: map size %d exceeds remaining buffer", size)
+    }
+
+    t := v.Type()
+    out := reflect.MakeMapWithSize(t, size)
+
+    for i := 0; i < size; i++ {
+        k := reflect.New(t.Key()).Elem()
+        n, err := decode(buf[off:], k)
+        if err != nil {
+            return 0, err
+        }
+        off += n
+
+        e := reflect.New(t.Elem()).Elem()
+        n, err = decode(buf[off:], e)
+        if err != nil {
+            return 0, err
+        }
+        off += n
+
+        out.SetMapIndex(k, e)
+    }
+
+    v.Set(out)
+    return off, nil
+}
+
+// skip consumes the payload of a value of wire type `typ` without
+// decoding it anywhere, so an unknown field (e.g. from a newer schema
+// version) doesn't throw off the offsets of the fields that follow it.
+func skip(buf []byte, typ compact.Type) (int, error) {
+    switch typ {
+        case compact.BOOL_TRUE, compact.BOOL_FALSE:
+            return 0, nil
+
+        case compact.BYTE:
+            if len(buf) < 1 {
+                return 0, fmt.Errorf("compact: truncated byte")
+            }
+            return 1, nil
+
+        case compact.I16, compact.I32:
+            _, n := compact.GetVarint32(buf)
+            if n == 0 {
+                return 0, fmt.Errorf("compact: truncated varint")
+            }
+            return n, nil
+
+        case compact.I64:
+            _, n := compact.GetVarint64(buf)
+            if n == 0 {
+                return 0, fmt.Errorf("compact: truncated varint")
+            }
+            return n, nil
+
+        case compact.DOUBLE:
+            if len(buf) < 8 {
+                return 0, fmt.Errorf("compact: truncated double")
+            }
+            return 8, nil
+
+        case compact.BINARY:
+            _, n, err := decodeBinary(buf)
+            return n, err
+
+        case compact.LIST, compact.SET:
+            return skipList(buf)
+
+        case compact.MAP:
+            return skipMap(buf)
+
+        case compact.STRUCT:
+            return skipStruct(buf)
+
+        default:
+            return 0, fmt.Errorf("compact: unknown wire type %d", typ)
+    }
+}
+
+func skipList(buf []byte) (int, error) {
+    if len(buf) < 1 {
+        return 0, fmt.Errorf("compact: truncated list header")
+    }
+
+    size, typ, long := compact.UnpackListHeader(buf[0])
+    off := 1
+
+    if long {
+        n, m := compact.GetVarint32(buf[off:])
+        if m == 0 {
+            return 0, fmt.Errorf("compact: truncated list size")
+        }
+        size, off = int(n), off+m
+    }
+
+    for i := 0; i < size; i++ {
+        n, err := skipElem(buf[off:], typ)
+        if err != nil {
+            return 0, err
+        }
+        off += n
+    }
+
+    return off, nil
+}
+
+// skipElem is skip for a list/set element or a map key/value, where a
+// bool is its own BOOL_TRUE/BOOL_FALSE byte rather than packed into a
+// field header.
+func skipElem(buf []byte, typ compact.Type) (int, error) {
+    if typ == compact.BOOL_TRUE || typ == compact.BOOL_FALSE {
+        if len(buf) < 1 {
+            return 0, fmt.Errorf("compact: truncated bool")
+        }
+        return 1, nil
+    }
+    return skip(buf, typ)
+}
+
+func skipMap(buf []byte) (int, error) {
+    size, keyType, valType, off, err := compact.UnpackMapHeader(buf)
+    if err != nil {
+        return 0, err
+    }
+
+    for i := 0; i < size; i++ {
+        n, err := skipElem(buf[off:], keyType)
+        if err != nil {
+            return 0, err
+        }
+        off += n
+
+        n, err = skipElem(buf[off:], valType)
+        if err != nil {
+            return 0, err
+        }
+        off += n
+    }
+
+    return off, nil
+}
+
+func skipStruct(buf []byte) (int, error) {
+    off := 0
+
+    for {
+        if off >= len(buf) {
+            return 0, fmt.Errorf("compact: truncated struct, missing STOP")
+        }
+
+        h := buf[off]
+        off++
+
+        delta, typ := compact.UnpackFieldHeader(h)
+        if typ == compact.STOP {
+            return off, nil
+        }
+
+        if delta == 0 {
+            /* long form: skip the zig-zagged absolute id first */
+            _, m := compact.GetVarint32(buf[off:])
+            if m == 0 {
+                return 0, fmt.Errorf("compact: truncated field id")
+            }
+            off += m
+        }
+
+        n, err := skip(buf[off:], typ)
+        if err != nil {
+            return 0, err
+        }
+        off += n
+    }
+}
+
+func decodeStruct(buf []byte, v reflect.Value) (int, error) {
+    t := v.Type()
+    off := 0
+    lastId := int16(0)
+
+    for {
+        if off >= len(buf) {
+            return 0, fmt.Errorf("compact: truncated struct, missing STOP")
+        }
+
+        h := buf[off]
+        off++
+
+        delta, typ := compact.UnpackFieldHeader(h)
+        if typ == compact.STOP {
+            break
+        }
+
+        id := lastId + delta
+        if delta == 0 {
+            x, n := compact.GetVarint32(buf[off:])
+            if n == 0 {
+                return 0, fmt.Errorf("compact: truncated field id")
+            }
+            id, off = int16(compact.UnZigZag32(x)), off+n
+        }
+
+        /* field IDs are matched against compact.FieldTag, not position,
+           so reordering/evolving the struct doesn't desync decode from
+           whatever produced the bytes (see compact.TagFieldId) */
+        idx, known := compact.FieldByTag(t, id)
+
+        /* a peer's wire data is not trusted to agree with the local
+           struct's type for a given field ID - schema evolution (or a
+           hostile payload) can put any wire type behind any ID, and
+           blindly calling SetBool/decode on a mismatched Go kind
+           panics instead of failing cleanly */
+        if known && !wireTypeMatches(typ, t.Field(idx).Type) {
+            return 0, fmt.Errorf("compact: field %s.%s: wire type %v does not fit Go type %s", t, t.Field(idx).Name, typ, t.Field(idx).Type)
+        }
+
+        switch {
+            case known && (typ == compact.BOOL_TRUE || typ == compact.BOOL_FALSE):
+                v.Field(idx).SetBool(typ == compact.BOOL_TRUE)
+
+            case known:
+                n, err := decode(buf[off:], v.Field(idx))
+                if err != nil {
+                    return 0, err
+                }
+                off += n
+
+            default:
+                /* unknown field: skip its payload so later fields still align */
+                n, err := skip(buf[off:], typ)
+                if err != nil {
+                    return 0, err
+                }
+                off += n
+        }
+
+        lastId = id
+    }
+
+    return off, nil
+}
@@ -0,0 +1,196 @@
+/*
+ * Copyright 2024 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package decoder
+
+import (
+    `bytes`
+    `testing`
+
+    `github.com/cloudwego/frugal/internal/compact`
+    `github.com/cloudwego/frugal/internal/compact/encoder`
+)
+
+type demoStruct struct {
+    Id     int64            `frugal:"1"`
+    Name   string           `frugal:"2"`
+    Active bool             `frugal:"3"`
+    Tags   []string         `frugal:"4"`
+    Scores map[string]int32 `frugal:"5"`
+}
+
+// encodeObject is a test helper that drives the real encoder.EncodeObject
+// through a bytes.Buffer and hands back the bytes it wrote, since
+// EncodeObject returns a byte count rather than the encoded slice.
+func encodeObject(t *testing.T, val interface{}) []byte {
+    t.Helper()
+
+    var mem bytes.Buffer
+    if _, err := encoder.EncodeObject(nil, &mem, val); err != nil {
+        t.Fatalf("EncodeObject: %v", err)
+    }
+
+    return mem.Bytes()
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+    in := demoStruct{
+        Id:     -12345,
+        Name:   "hello",
+        Active: true,
+        Tags:   []string{"a", "b", "c"},
+        Scores: map[string]int32{"x": 1, "y": -2},
+    }
+
+    buf := encodeObject(t, &in)
+
+    var out demoStruct
+    n, err := DecodeObject(buf, &out)
+    if err != nil {
+        t.Fatalf("DecodeObject: %v", err)
+    }
+    if n != len(buf) {
+        t.Fatalf("DecodeObject consumed %d bytes, want %d", n, len(buf))
+    }
+
+    if out.Id != in.Id || out.Name != in.Name || out.Active != in.Active {
+        t.Fatalf("DecodeObject = %+v, want %+v", out, in)
+    }
+    if len(out.Tags) != len(in.Tags) {
+        t.Fatalf("Tags = %v, want %v", out.Tags, in.Tags)
+    }
+    for i := range in.Tags {
+        if out.Tags[i] != in.Tags[i] {
+            t.Fatalf("Tags[%d] = %q, want %q", i, out.Tags[i], in.Tags[i])
+        }
+    }
+    if len(out.Scores) != len(in.Scores) {
+        t.Fatalf("Scores = %v, want %v", out.Scores, in.Scores)
+    }
+    for k, v := range in.Scores {
+        if out.Scores[k] != v {
+            t.Fatalf("Scores[%q] = %d, want %d", k, out.Scores[k], v)
+        }
+    }
+}
+
+func TestEncodeDecodeRoundTripBoolList(t *testing.T) {
+    // list<bool> and a bare top-level bool both go through decode()
+    // directly rather than decodeStruct's header-nibble special case.
+    in := []bool{true, false, true}
+    buf := encodeObject(t, in)
+
+    var out []bool
+    if _, err := DecodeObject(buf, &out); err != nil {
+        t.Fatalf("DecodeObject: %v", err)
+    }
+    if len(out) != len(in) {
+        t.Fatalf("DecodeObject = %v, want %v", out, in)
+    }
+    for i := range in {
+        if out[i] != in[i] {
+            t.Fatalf("DecodeObject[%d] = %v, want %v", i, out[i], in[i])
+        }
+    }
+}
+
+func TestEncodeDecodeRoundTripBoolMapValue(t *testing.T) {
+    in := map[int16]bool{1: true, 2: false}
+    buf := encodeObject(t, in)
+
+    var out map[int16]bool
+    if _, err := DecodeObject(buf, &out); err != nil {
+        t.Fatalf("DecodeObject: %v", err)
+    }
+    if len(out) != len(in) {
+        t.Fatalf("DecodeObject = %v, want %v", out, in)
+    }
+    for k, v := range in {
+        if out[k] != v {
+            t.Fatalf("DecodeObject[%d] = %v, want %v", k, out[k], v)
+        }
+    }
+}
+
+func TestDecodeUnknownFieldIsSkipped(t *testing.T) {
+    type withExtra struct {
+        Id    int16 `frugal:"1"`
+        Extra int16 `frugal:"9"`
+    }
+    type withoutExtra struct {
+        Id int16 `frugal:"1"`
+    }
+
+    buf := encodeObject(t, &withExtra{Id: 1, Extra: 99})
+
+    var out withoutExtra
+    if _, err := DecodeObject(buf, &out); err != nil {
+        t.Fatalf("DecodeObject: %v", err)
+    }
+    if out.Id != 1 {
+        t.Fatalf("out.Id = %d, want 1", out.Id)
+    }
+}
+
+func TestDecodeFieldKindMismatchReturnsError(t *testing.T) {
+    // A BOOL_TRUE field at id 1 (header 0x11, no payload), STOP.
+    // Reproduces a schema where the peer's field 1 is a bool but the
+    // local struct declares it as an int16 - this must not panic.
+    buf := []byte{0x11, 0x00}
+
+    var out struct {
+        A int16 `frugal:"1"`
+    }
+
+    if _, err := DecodeObject(buf, &out); err == nil {
+        t.Fatalf("expected an error for a wire-type/Go-kind mismatch, got nil")
+    }
+}
+
+func TestDecodeListSizeBoundedByBuffer(t *testing.T) {
+    // A long-form list header claiming 100 million I64 elements, with
+    // no element bytes actually behind it. MakeSlice must never be
+    // reached with that size.
+    h, _ := compact.PackListHeader(100000000, compact.I64)
+    buf := append([]byte{h}, compact.PutVarint32(nil, 100000000)...)
+
+    var out []int64
+    if _, err := DecodeObject(buf, &out); err == nil {
+        t.Fatalf("expected an error for a list size exceeding the buffer, got nil")
+    }
+}
+
+func TestDecodeMapSizeBoundedByBuffer(t *testing.T) {
+    // A valid map header (varint size + key/value type byte) claiming
+    // 100 million entries, with no entries actually behind it.
+    buf := compact.PackMapHeader(nil, 100000000, compact.I32, compact.I32)
+
+    var out map[int16]int16
+    if _, err := DecodeObject(buf, &out); err == nil {
+        t.Fatalf("expected an error for a map size exceeding the buffer, got nil")
+    }
+}
+
+func TestDecodeTruncatedStructMissingStop(t *testing.T) {
+    buf := []byte{byte(1)<<4 | byte(compact.I16), 2}
+
+    var out struct {
+        A int16 `frugal:"1"`
+    }
+    if _, err := DecodeObject(buf, &out); err == nil {
+        t.Fatalf("expected an error for a struct with no STOP byte")
+    }
+}
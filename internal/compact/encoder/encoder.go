@@ -0,0 +1,259 @@
+/*
+ * Copyright 2024 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package encoder implements the Thrift Compact Protocol encoder. It
+// sits next to internal/binary/encoder and, like it, is meant to
+// eventually compile a per-type program through the shared SSA pipeline
+// in internal/atm/ssa so that both protocols get the same register
+// allocator and native codegen. See the TODO(compact-jit) note on
+// package compact: that backend work has not happened yet, so this is
+// a reflection-based stopgap, not "the" Compact Protocol implementation
+// in its intended final form. EncodeObject walks the value with
+// reflection; callers only see the difference as slower encodes, not a
+// different API or wire format, but should not expect JIT'd throughput.
+//
+// Because this reflection path has no generated-code metadata to lean
+// on, it requires every struct field to be tagged with its real Thrift
+// field ID (see compact.FieldTag); a struct without that tag is
+// rejected rather than encoded with invented, possibly-wrong IDs.
+package encoder
+
+import (
+    `encoding/binary`
+    `fmt`
+    `math`
+    `reflect`
+
+    `github.com/cloudwego/frugal/internal/compact`
+    `github.com/cloudwego/frugal/iov`
+)
+
+// EncodedSize measures the Compact-Protocol encoded size of val.
+func EncodedSize(val interface{}) int {
+    buf, err := encode(nil, reflect.ValueOf(val))
+    if err != nil {
+        return 0
+    }
+    return len(buf)
+}
+
+// EncodeObject serializes val into buf with the Thrift Compact Protocol.
+func EncodeObject(buf []byte, mem iov.BufferWriter, val interface{}) (int, error) {
+    out, err := encode(buf[:0], reflect.ValueOf(val))
+
+    if err != nil {
+        return 0, err
+    }
+
+    if mem != nil {
+        if _, err := mem.Write(out); err != nil {
+            return 0, err
+        }
+    }
+
+    return len(out), nil
+}
+
+// wireType maps a Go kind to the Compact Protocol type code used in
+// field and element headers.
+func wireType(v reflect.Value) compact.Type {
+    switch v.Kind() {
+        case reflect.Bool                          : return compact.BOOL_TRUE
+        case reflect.Int8, reflect.Uint8            : return compact.BYTE
+        case reflect.Int16                          : return compact.I16
+        case reflect.Int32                          : return compact.I32
+        case reflect.Int64, reflect.Int             : return compact.I64
+        case reflect.Float64, reflect.Float32       : return compact.DOUBLE
+        case reflect.String                         : return compact.BINARY
+        case reflect.Map                            : return compact.MAP
+        case reflect.Struct                         : return compact.STRUCT
+        case reflect.Slice, reflect.Array:
+            if v.Type().Elem().Kind() == reflect.Uint8 {
+                return compact.BINARY
+            }
+            return compact.LIST
+        default:
+            return compact.STRUCT
+    }
+}
+
+func encode(buf []byte, v reflect.Value) ([]byte, error) {
+    for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+        if v.IsNil() {
+            return buf, nil
+        }
+        v = v.Elem()
+    }
+
+    switch v.Kind() {
+        case reflect.Bool:
+            /* a struct field's bool is packed into its header nibble
+               instead (see encodeStruct); everywhere else - list/set
+               elements, map keys/values, a bare top-level bool - it's
+               written as its own BOOL_TRUE/BOOL_FALSE byte */
+            if v.Bool() {
+                return append(buf, byte(compact.BOOL_TRUE)), nil
+            } else {
+                return append(buf, byte(compact.BOOL_FALSE)), nil
+            }
+
+        case reflect.Int8:
+            return append(buf, byte(v.Int())), nil
+
+        case reflect.Uint8:
+            return append(buf, byte(v.Uint())), nil
+
+        case reflect.Int16, reflect.Int32:
+            return compact.PutVarint32(buf, compact.ZigZag32(int32(v.Int()))), nil
+
+        case reflect.Int64, reflect.Int:
+            return compact.PutVarint64(buf, compact.ZigZag64(v.Int())), nil
+
+        case reflect.Float32, reflect.Float64:
+            var out [8]byte
+            binary.BigEndian.PutUint64(out[:], math.Float64bits(v.Float()))
+            return append(buf, out[:]...), nil
+
+        case reflect.String:
+            return encodeBinary(buf, []byte(v.String())), nil
+
+        case reflect.Slice, reflect.Array:
+            if v.Type().Elem().Kind() == reflect.Uint8 {
+                return encodeBinary(buf, v.Bytes()), nil
+            }
+            return encodeList(buf, v)
+
+        case reflect.Map:
+            return encodeMap(buf, v)
+
+        case reflect.Struct:
+            return encodeStruct(buf, v)
+
+        default:
+            return buf, nil
+    }
+}
+
+func encodeBinary(buf []byte, p []byte) []byte {
+    buf = compact.PutVarint32(buf, uint32(len(p)))
+    return append(buf, p...)
+}
+
+func encodeList(buf []byte, v reflect.Value) ([]byte, error) {
+    n := v.Len()
+    elemType := compact.STRUCT
+
+    if n > 0 {
+        elemType = wireType(v.Index(0))
+    }
+
+    h, long := compact.PackListHeader(n, elemType)
+    buf = append(buf, h)
+
+    if long {
+        buf = compact.PutVarint32(buf, uint32(n))
+    }
+
+    var err error
+    for i := 0; i < n; i++ {
+        if buf, err = encode(buf, v.Index(i)); err != nil {
+            return nil, err
+        }
+    }
+
+    return buf, nil
+}
+
+func encodeMap(buf []byte, v reflect.Value) ([]byte, error) {
+    keys := v.MapKeys()
+    t := v.Type()
+
+    keyType := wireType(reflect.Zero(t.Key()))
+    valType := wireType(reflect.Zero(t.Elem()))
+    buf = compact.PackMapHeader(buf, len(keys), keyType, valType)
+
+    var err error
+    for _, k := range keys {
+        if buf, err = encode(buf, k); err != nil {
+            return nil, err
+        }
+        if buf, err = encode(buf, v.MapIndex(k)); err != nil {
+            return nil, err
+        }
+    }
+
+    return buf, nil
+}
+
+// encodeStruct packs each field's header using the delta-ID short form
+// when it fits, falling back to a zero nibble plus a zig-zagged
+// absolute ID otherwise. Booleans are special-cased per the Compact
+// Protocol spec: their value is packed directly into the header's type
+// nibble (BOOL_TRUE/BOOL_FALSE) instead of being written as a payload.
+//
+// Every exported field must carry a compact.FieldTag naming its real
+// Thrift field ID; declaration order is not a substitute; a struct with
+// non-contiguous or reordered IDs (the normal result of schema
+// evolution) would otherwise silently desync from its Binary Protocol
+// encoding of the same value.
+func encodeStruct(buf []byte, v reflect.Value) ([]byte, error) {
+    var err error
+    var lastId int16
+
+    t := v.Type()
+
+    for i := 0; i < t.NumField(); i++ {
+        fv := v.Field(i)
+        sf := t.Field(i)
+
+        if sf.PkgPath != "" {
+            continue // unexported
+        }
+
+        if fv.Kind() == reflect.Ptr && fv.IsNil() {
+            continue
+        }
+
+        id, ok := compact.TagFieldId(sf)
+        if !ok {
+            return nil, fmt.Errorf("compact: field %s.%s has no %q tag with its Thrift field ID", t, sf.Name, compact.FieldTag)
+        }
+
+        typ := wireType(fv)
+
+        if typ == compact.BOOL_TRUE && !fv.Bool() {
+            typ = compact.BOOL_FALSE
+        }
+
+        if h, ok := compact.PackFieldHeader(lastId, id, typ); ok {
+            buf = append(buf, h)
+        } else {
+            buf = append(buf, byte(typ)&0x0f)
+            buf = compact.PutVarint32(buf, compact.ZigZag32(int32(id)))
+        }
+
+        /* booleans carry no payload; their value lives in the type nibble */
+        if typ != compact.BOOL_TRUE && typ != compact.BOOL_FALSE {
+            if buf, err = encode(buf, fv); err != nil {
+                return nil, err
+            }
+        }
+
+        lastId = id
+    }
+
+    return append(buf, byte(compact.STOP)), nil
+}
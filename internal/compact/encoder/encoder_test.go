@@ -0,0 +1,122 @@
+/*
+ * Copyright 2024 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package encoder
+
+import (
+    `bytes`
+    `testing`
+
+    `github.com/cloudwego/frugal/internal/compact`
+)
+
+type flatStruct struct {
+    A int16 `frugal:"1"`
+    B bool  `frugal:"2"`
+    C bool  `frugal:"3"`
+}
+
+// encodeObject is a test helper that drives the real EncodeObject through
+// a bytes.Buffer and hands back the bytes it wrote, since EncodeObject
+// itself returns a byte count rather than the encoded slice.
+func encodeObject(t *testing.T, val interface{}) []byte {
+    t.Helper()
+
+    var mem bytes.Buffer
+    n, err := EncodeObject(nil, &mem, val)
+    if err != nil {
+        t.Fatalf("EncodeObject: %v", err)
+    }
+    if n != mem.Len() {
+        t.Fatalf("EncodeObject returned n=%d, but wrote %d bytes to mem", n, mem.Len())
+    }
+
+    return mem.Bytes()
+}
+
+func TestEncodeStructPacksBoolsIntoFieldHeader(t *testing.T) {
+    buf := encodeObject(t, &flatStruct{A: 7, B: true, C: false})
+
+    /* field 1 (I16 short form), zig-zagged 7, field 2 (BOOL_TRUE short
+       form, no payload), field 3 (BOOL_FALSE short form, no payload),
+       STOP */
+    want := []byte{
+        byte(1)<<4 | byte(compact.I16), 14,
+        byte(1)<<4 | byte(compact.BOOL_TRUE),
+        byte(1)<<4 | byte(compact.BOOL_FALSE),
+        byte(compact.STOP),
+    }
+
+    if len(buf) != len(want) {
+        t.Fatalf("EncodeObject(flatStruct) = %v (len %d), want %v (len %d)", buf, len(buf), want, len(want))
+    }
+    for i := range want {
+        if buf[i] != want[i] {
+            t.Fatalf("EncodeObject(flatStruct)[%d] = %#x, want %#x (full: %v)", i, buf[i], want[i], buf)
+        }
+    }
+}
+
+func TestEncodeStructRejectsUntaggedField(t *testing.T) {
+    type untagged struct {
+        A int16
+    }
+
+    if _, err := EncodeObject(nil, nil, &untagged{A: 1}); err == nil {
+        t.Fatalf("expected an error for a field with no frugal tag")
+    }
+}
+
+func TestEncodeStructSkipsUnexportedAndNilPointerFields(t *testing.T) {
+    type withExtras struct {
+        A       int16 `frugal:"1"`
+        unexp   string
+        Skipped *int16 `frugal:"3"`
+    }
+
+    buf := encodeObject(t, &withExtras{A: 5})
+
+    /* only field 1 and STOP should appear; neither the unexported field
+       (which also lacks a tag) nor the nil pointer field should be
+       written */
+    want := []byte{byte(1)<<4 | byte(compact.I16), 10, byte(compact.STOP)}
+    if len(buf) != len(want) {
+        t.Fatalf("EncodeObject(withExtras) = %v, want %v", buf, want)
+    }
+}
+
+func TestEncodeMapWritesKeyValueTypeByte(t *testing.T) {
+    m := map[int16]string{1: "x"}
+    buf := encodeObject(t, m)
+
+    size, keyType, valType, n, err := compact.UnpackMapHeader(buf)
+    if err != nil {
+        t.Fatalf("UnpackMapHeader: %v", err)
+    }
+    if size != 1 || keyType != compact.I16 || valType != compact.BINARY {
+        t.Fatalf("UnpackMapHeader = (%d, %v, %v), want (1, %v, %v)", size, keyType, valType, compact.I16, compact.BINARY)
+    }
+    if n >= len(buf) {
+        t.Fatalf("map header consumed the whole buffer, leaving nothing for the entry")
+    }
+}
+
+func TestEncodeEmptyMapOmitsTypeByte(t *testing.T) {
+    buf := encodeObject(t, map[int16]string{})
+    if len(buf) != 1 || buf[0] != 0 {
+        t.Fatalf("EncodeObject(empty map) = %v, want a single zero byte", buf)
+    }
+}
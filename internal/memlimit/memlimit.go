@@ -0,0 +1,81 @@
+/*
+ * Copyright 2024 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package memlimit
+
+import (
+    `os`
+    `strconv`
+)
+
+// Effective returns the memory limit the current process should budget
+// against, in bytes, and whether a limit could be determined at all.
+//
+// GOMEMLIMIT takes precedence when set, since the user asked for it
+// explicitly. Otherwise the platform-specific cgroup probe in
+// memlimit_linux.go (or the no-op in memlimit_other.go) is consulted.
+// Callers should treat a `false` result as "no usable limit, fall back
+// to host memory".
+func Effective() (int64, bool) {
+    if v, ok := fromEnv(); ok {
+        return v, true
+    } else {
+        return fromCgroup()
+    }
+}
+
+// fromEnv reads GOMEMLIMIT the same way the Go runtime does: a decimal
+// number of bytes, optionally suffixed with B/KiB/MiB/GiB/TiB. "off" or an
+// unset/empty value means "no limit from the environment".
+func fromEnv() (int64, bool) {
+    v := os.Getenv("GOMEMLIMIT")
+    if v == "" || v == "off" {
+        return 0, false
+    }
+    if n, ok := parseMemSize(v); ok && n > 0 {
+        return n, true
+    }
+    return 0, false
+}
+
+func parseMemSize(s string) (int64, bool) {
+    mul := int64(1)
+
+    /* recognize the same binary suffixes as debug.SetMemoryLimit's docs;
+       the bare "B" comes last so it never shadows "KiB" and friends */
+    for _, sfx := range []struct {
+        suffix string
+        m      int64
+    }{
+        {"TiB", 1 << 40},
+        {"GiB", 1 << 30},
+        {"MiB", 1 << 20},
+        {"KiB", 1 << 10},
+        {"B", 1},
+    } {
+        if len(s) > len(sfx.suffix) && s[len(s)-len(sfx.suffix):] == sfx.suffix {
+            s, mul = s[:len(s)-len(sfx.suffix)], sfx.m
+            break
+        }
+    }
+
+    /* parse the remaining numeric portion */
+    n, err := strconv.ParseInt(s, 10, 64)
+    if err != nil {
+        return 0, false
+    }
+    return n * mul, true
+}
@@ -0,0 +1,147 @@
+/*
+ * Copyright 2024 ByteDance Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package memlimit
+
+import (
+    `os`
+    `strconv`
+    `strings`
+)
+
+// cgroup v2 exposes a single unified file; v1 splits the limit and the
+// controller mount point across two files that have to be resolved
+// through /proc/self/cgroup.
+const (
+    cgroupV2Limit  = "/sys/fs/cgroup/memory.max"
+    cgroupV1Self   = "/proc/self/cgroup"
+    cgroupV1Mounts = "/proc/self/mountinfo"
+)
+
+// fromCgroup returns the effective memory limit imposed on this process
+// by its cgroup, preferring v2 and falling back to v1. It returns false
+// if no limit is in effect (cgroup says "max"/"unlimited", or the files
+// can't be read at all, e.g. not actually running under a cgroup).
+func fromCgroup() (int64, bool) {
+    if v, ok := readCgroupV2(); ok {
+        return v, true
+    }
+    return readCgroupV1()
+}
+
+func readCgroupV2() (int64, bool) {
+    data, err := os.ReadFile(cgroupV2Limit)
+    if err != nil {
+        return 0, false
+    }
+    return parseCgroupValue(string(data))
+}
+
+func readCgroupV1() (int64, bool) {
+    dir, ok := cgroupV1MemoryDir()
+    if !ok {
+        return 0, false
+    }
+
+    /* "memory.limit_in_bytes" is the v1 equivalent of v2's "memory.max" */
+    data, err := os.ReadFile(dir + "/memory.limit_in_bytes")
+    if err != nil {
+        return 0, false
+    }
+
+    return parseCgroupValue(string(data))
+}
+
+// cgroupV1MemoryDir resolves the filesystem path of the "memory"
+// controller for the current process by cross-referencing its cgroup
+// membership against the mounted controller hierarchies.
+func cgroupV1MemoryDir() (string, bool) {
+    rel, ok := cgroupV1MemoryPath()
+    if !ok {
+        return "", false
+    }
+
+    mounts, err := os.ReadFile(cgroupV1Mounts)
+    if err != nil {
+        return "", false
+    }
+
+    for _, line := range strings.Split(string(mounts), "\n") {
+        fields := strings.Fields(line)
+
+        /* mountinfo splits the super-options after a lone "-" separator */
+        sep := indexOf(fields, "-")
+        if sep < 0 || sep+3 >= len(fields) {
+            continue
+        }
+        if fields[sep+1] != "cgroup" {
+            continue
+        }
+        for _, opt := range strings.Split(fields[sep+3], ",") {
+            if opt == "memory" {
+                return fields[4] + "/" + strings.TrimPrefix(rel, "/"), true
+            }
+        }
+    }
+
+    return "", false
+}
+
+func cgroupV1MemoryPath() (string, bool) {
+    data, err := os.ReadFile(cgroupV1Self)
+    if err != nil {
+        return "", false
+    }
+
+    for _, line := range strings.Split(string(data), "\n") {
+        parts := strings.SplitN(line, ":", 3)
+        if len(parts) != 3 {
+            continue
+        }
+        for _, ctrl := range strings.Split(parts[1], ",") {
+            if ctrl == "memory" {
+                return parts[2], true
+            }
+        }
+    }
+
+    return "", false
+}
+
+func indexOf(fields []string, s string) int {
+    for i, f := range fields {
+        if f == s {
+            return i
+        }
+    }
+    return -1
+}
+
+func parseCgroupValue(s string) (int64, bool) {
+    s = strings.TrimSpace(s)
+
+    /* "max" (v2) and the i64 sentinel (v1) both mean "no limit" */
+    if s == "max" {
+        return 0, false
+    }
+
+    n, err := strconv.ParseInt(s, 10, 64)
+    if err != nil || n <= 0 || n >= 1<<62 {
+        return 0, false
+    }
+
+    return n, true
+}